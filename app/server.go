@@ -0,0 +1,57 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/config"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// Server is the root of the running Mattermost instance. It is assembled by NewServer from
+// a set of Options, which is how tests substitute pieces like the config store without
+// reaching into its fields directly.
+type Server struct {
+	configStore config.Store
+}
+
+// Option changes the behavior of Server during creation.
+type Option func(s *Server) error
+
+// NewServer creates a Server, applying the given options in order.
+func NewServer(options ...Option) (*Server, error) {
+	s := &Server{}
+
+	for _, option := range options {
+		if err := option(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.configStore == nil {
+		configStore, err := config.NewFileStore("config.json", true)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load config")
+		}
+		s.configStore = configStore
+	}
+
+	return s, nil
+}
+
+// ConfigStore sets the config.Store used by the server. Tests use this to inject a
+// config.MemoryStore seeded with a specific configuration instead of always constructing a
+// FileStore and touching the disk.
+func ConfigStore(store config.Store) Option {
+	return func(s *Server) error {
+		s.configStore = store
+		return nil
+	}
+}
+
+// Config returns the server's current configuration.
+func (s *Server) Config() *model.Config {
+	return s.configStore.Get()
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-server/config"
+)
+
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Configuration",
+}
+
+var ConfigEncryptCmd = &cobra.Command{
+	Use:     "encrypt [path]",
+	Short:   "Encrypt a configuration file",
+	Long:    "Encrypt the config.json at the given path in place, using the key from MM_CONFIG_KEY or MM_CONFIG_KEYFILE. A file that's already encrypted is left untouched.",
+	Example: "  config encrypt config/config.json",
+	Args:    cobra.ExactArgs(1),
+	RunE:    configEncryptCmdF,
+}
+
+var ConfigDecryptCmd = &cobra.Command{
+	Use:     "decrypt [path]",
+	Short:   "Decrypt a configuration file",
+	Long:    "Decrypt the encrypted config.json at the given path in place, using the key from MM_CONFIG_KEY or MM_CONFIG_KEYFILE. A file that's already plaintext is left untouched.",
+	Example: "  config decrypt config/config.json",
+	Args:    cobra.ExactArgs(1),
+	RunE:    configDecryptCmdF,
+}
+
+func init() {
+	ConfigCmd.AddCommand(
+		ConfigEncryptCmd,
+		ConfigDecryptCmd,
+	)
+	RootCmd.AddCommand(ConfigCmd)
+}
+
+func configEncryptCmdF(command *cobra.Command, args []string) error {
+	key, err := config.LoadEncryptionKey()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return errors.New("no config encryption key set; set MM_CONFIG_KEY or MM_CONFIG_KEYFILE")
+	}
+
+	return config.EncryptFile(args[0], key)
+}
+
+func configDecryptCmdF(command *cobra.Command, args []string) error {
+	key, err := config.LoadEncryptionKey()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return errors.New("no config encryption key set; set MM_CONFIG_KEY or MM_CONFIG_KEYFILE")
+	}
+
+	return config.DecryptFile(args[0], key)
+}
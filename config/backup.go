@@ -0,0 +1,200 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/mlog"
+)
+
+// defaultMaxBackups is how many rolling backups FileStore keeps when none is configured via
+// SetMaxBackups.
+const defaultMaxBackups = 5
+
+// backupTimeFormat gives each backup a unique, lexically-sortable suffix even when several
+// are written within the same second.
+const backupTimeFormat = "20060102150405.000000000"
+
+// BackupInfo describes a single rolling backup of config.json.
+type BackupInfo struct {
+	Name    string
+	ModTime time.Time
+	Size    int64
+}
+
+// SetMaxBackups configures how many rolling backups persist keeps, pruning older ones on the
+// next write. A value of 0 restores the default of 5.
+func (fs *FileStore) SetMaxBackups(n int) {
+	fs.configLock.Lock()
+	defer fs.configLock.Unlock()
+
+	fs.maxBackups = n
+}
+
+func (fs *FileStore) backupLimit() int {
+	if fs.maxBackups > 0 {
+		return fs.maxBackups
+	}
+
+	return defaultMaxBackups
+}
+
+// backupPath returns the path a backup taken at t would be written to.
+func (fs *FileStore) backupPath(t time.Time) string {
+	return fmt.Sprintf("%s.bak.%s", fs.path, t.UTC().Format(backupTimeFormat))
+}
+
+// backupGlob matches every backup path written by rotateBackup.
+func (fs *FileStore) backupGlob() string {
+	return fs.path + ".bak.*"
+}
+
+// writeAtomicFileMode is the permission config.json is written with when it doesn't already
+// exist; writeAtomic otherwise preserves whatever mode the file already has.
+const writeAtomicFileMode = 0644
+
+// writeAtomic writes b to a temp file in the same directory as fs.path and renames it into
+// place, so a crash mid-write can never leave a truncated or corrupt config.json. Before the
+// rename, it rotates the existing file into a timestamped backup.
+func (fs *FileStore) writeAtomic(b []byte) error {
+	dir := filepath.Dir(fs.path)
+
+	mode := os.FileMode(writeAtomicFileMode)
+	if info, err := os.Stat(fs.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(dir, filepath.Base(fs.path)+".tmp.*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp file")
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write temp file")
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to sync temp file")
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to close temp file")
+	}
+
+	// ioutil.TempFile creates the file mode 0600, and os.Rename would otherwise carry that
+	// onto config.json, silently tightening its permissions on every write.
+	if err = os.Chmod(tmpPath, mode); err != nil {
+		return errors.Wrap(err, "failed to set permissions on temp file")
+	}
+
+	if err = fs.rotateBackup(); err != nil {
+		mlog.Error("failed to rotate config backup", mlog.Err(err))
+	}
+
+	if err = os.Rename(tmpPath, fs.path); err != nil {
+		return errors.Wrap(err, "failed to rename temp file into place")
+	}
+
+	return nil
+}
+
+// rotateBackup copies the current config.json, if any, to a new timestamped backup and prunes
+// old backups beyond the configured limit.
+func (fs *FileStore) rotateBackup() error {
+	existing, err := ioutil.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to read %s for backup", fs.path)
+	}
+
+	backupPath := fs.backupPath(time.Now())
+	if err = ioutil.WriteFile(backupPath, existing, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write backup %s", backupPath)
+	}
+
+	return fs.pruneBackups()
+}
+
+// pruneBackups removes the oldest backups beyond the configured limit.
+func (fs *FileStore) pruneBackups() error {
+	backups := fs.Backups()
+
+	limit := fs.backupLimit()
+	if len(backups) <= limit {
+		return nil
+	}
+
+	dir := filepath.Dir(fs.path)
+	for _, b := range backups[limit:] {
+		if err := os.Remove(filepath.Join(dir, b.Name)); err != nil {
+			return errors.Wrapf(err, "failed to remove old backup %s", b.Name)
+		}
+	}
+
+	return nil
+}
+
+// Backups returns the rolling backups of config.json currently on disk, newest first.
+func (fs *FileStore) Backups() []BackupInfo {
+	matches, err := filepath.Glob(fs.backupGlob())
+	if err != nil {
+		mlog.Error("failed to list config backups", mlog.Err(err))
+		return nil
+	}
+
+	backups := make([]BackupInfo, 0, len(matches))
+	for _, m := range matches {
+		info, statErr := os.Stat(m)
+		if statErr != nil {
+			continue
+		}
+
+		backups = append(backups, BackupInfo{
+			Name:    filepath.Base(m),
+			ModTime: info.ModTime(),
+			Size:    info.Size(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Name > backups[j].Name
+	})
+
+	return backups
+}
+
+// RestoreBackup replaces config.json with the contents of the named backup (as returned by
+// Backups) and reloads the configuration from it.
+func (fs *FileStore) RestoreBackup(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || !strings.HasPrefix(name, filepath.Base(fs.path)+".bak.") {
+		return fmt.Errorf("invalid backup name %q", name)
+	}
+
+	backupPath := filepath.Join(filepath.Dir(fs.path), name)
+
+	b, err := ioutil.ReadFile(backupPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read backup %s", name)
+	}
+
+	if err = fs.writeAtomic(b); err != nil {
+		return errors.Wrap(err, "failed to restore backup")
+	}
+
+	return fs.Load()
+}
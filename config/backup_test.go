@@ -0,0 +1,174 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestFileStore(t *testing.T) (*FileStore, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "config-backup-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return &FileStore{path: filepath.Join(dir, "config.json")}, dir
+}
+
+func TestFileStoreBackupPathAndGlob(t *testing.T) {
+	fs, dir := newTestFileStore(t)
+
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	path := fs.backupPath(at)
+	if filepath.Dir(path) != dir {
+		t.Errorf("backupPath dir = %q, want %q", filepath.Dir(path), dir)
+	}
+	if filepath.Base(path) != "config.json.bak.20200102030405.000000000" {
+		t.Errorf("backupPath = %q", filepath.Base(path))
+	}
+
+	if got, want := fs.backupGlob(), fs.path+".bak.*"; got != want {
+		t.Errorf("backupGlob() = %q, want %q", got, want)
+	}
+}
+
+func TestFileStoreWriteAtomicAndRotateBackup(t *testing.T) {
+	fs, _ := newTestFileStore(t)
+
+	if err := fs.writeAtomic([]byte("v1")); err != nil {
+		t.Fatalf("writeAtomic v1 failed: %v", err)
+	}
+	if backups := fs.Backups(); len(backups) != 0 {
+		t.Fatalf("expected no backups after the first write, got %d", len(backups))
+	}
+
+	if err := fs.writeAtomic([]byte("v2")); err != nil {
+		t.Fatalf("writeAtomic v2 failed: %v", err)
+	}
+	backups := fs.Backups()
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup after the second write, got %d", len(backups))
+	}
+
+	b, err := ioutil.ReadFile(fs.path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if string(b) != "v2" {
+		t.Errorf("config.json = %q, want %q", b, "v2")
+	}
+
+	backedUp, err := ioutil.ReadFile(filepath.Join(filepath.Dir(fs.path), backups[0].Name))
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if string(backedUp) != "v1" {
+		t.Errorf("backup content = %q, want %q", backedUp, "v1")
+	}
+}
+
+func TestFileStoreWriteAtomicPreservesExistingMode(t *testing.T) {
+	fs, _ := newTestFileStore(t)
+
+	if err := fs.writeAtomic([]byte("v1")); err != nil {
+		t.Fatalf("writeAtomic v1 failed: %v", err)
+	}
+	if err := os.Chmod(fs.path, 0600); err != nil {
+		t.Fatalf("failed to chmod config.json: %v", err)
+	}
+
+	if err := fs.writeAtomic([]byte("v2")); err != nil {
+		t.Fatalf("writeAtomic v2 failed: %v", err)
+	}
+
+	info, err := os.Stat(fs.path)
+	if err != nil {
+		t.Fatalf("failed to stat config.json: %v", err)
+	}
+	if got, want := info.Mode().Perm(), os.FileMode(0600); got != want {
+		t.Errorf("config.json mode = %v, want %v (the mode it had before the write)", got, want)
+	}
+}
+
+func TestFileStoreWriteAtomicDefaultsModeOnFirstWrite(t *testing.T) {
+	fs, _ := newTestFileStore(t)
+
+	if err := fs.writeAtomic([]byte("v1")); err != nil {
+		t.Fatalf("writeAtomic failed: %v", err)
+	}
+
+	info, err := os.Stat(fs.path)
+	if err != nil {
+		t.Fatalf("failed to stat config.json: %v", err)
+	}
+	if got, want := info.Mode().Perm(), os.FileMode(writeAtomicFileMode); got != want {
+		t.Errorf("config.json mode = %v, want %v", got, want)
+	}
+}
+
+func TestFileStorePruneBackupsRespectsLimit(t *testing.T) {
+	fs, _ := newTestFileStore(t)
+	fs.SetMaxBackups(2)
+
+	for i := 0; i < 4; i++ {
+		if err := fs.writeAtomic([]byte{byte('0' + i)}); err != nil {
+			t.Fatalf("writeAtomic #%d failed: %v", i, err)
+		}
+	}
+
+	backups := fs.Backups()
+	if len(backups) != 2 {
+		t.Fatalf("expected pruning to keep 2 backups, got %d: %v", len(backups), backups)
+	}
+}
+
+func TestFileStoreRestoreBackupRejectsInvalidNames(t *testing.T) {
+	fs, _ := newTestFileStore(t)
+
+	invalidNames := []string{"", "../config.json.bak.123", "/etc/passwd", "notabackup"}
+	for _, name := range invalidNames {
+		if err := fs.RestoreBackup(name); err == nil {
+			t.Errorf("RestoreBackup(%q) should have failed", name)
+		}
+	}
+}
+
+func TestFileStoreRestoreBackupRoundTrip(t *testing.T) {
+	fs, _ := newTestFileStore(t)
+
+	if err := fs.writeAtomic([]byte(`{}`)); err != nil {
+		t.Fatalf("writeAtomic #1 failed: %v", err)
+	}
+	if err := fs.writeAtomic([]byte(`{"ServiceSettings":{"SiteURL":"http://example.com"}}`)); err != nil {
+		t.Fatalf("writeAtomic #2 failed: %v", err)
+	}
+
+	backups := fs.Backups()
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup, got %d", len(backups))
+	}
+
+	if err := fs.RestoreBackup(backups[0].Name); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(fs.path)
+	if err != nil {
+		t.Fatalf("failed to read restored config: %v", err)
+	}
+	if string(b) != "{}" {
+		t.Errorf("config.json = %q, want the restored backup contents %q", b, "{}")
+	}
+
+	if cfg := fs.Get(); cfg == nil {
+		t.Error("expected Load (triggered by RestoreBackup) to populate the in-memory config")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package config
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// ConfigChange describes a single field, identified by its dotted path (e.g.
+// "ServiceSettings.SiteURL"), that differs between two configurations.
+type ConfigChange struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// Diff returns the set of fields that differ between old and new, each identified by its
+// dotted path along with its old and new value. This gives plugins, the audit log, and
+// internal subsystems a single well-defined way to see exactly what changed.
+func Diff(old, new *model.Config) []ConfigChange {
+	var changes []ConfigChange
+
+	if old == nil || new == nil {
+		return changes
+	}
+
+	diffStruct(reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem(), "", &changes)
+
+	return changes
+}
+
+func diffStruct(oldV, newV reflect.Value, path string, changes *[]ConfigChange) {
+	t := oldV.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldPath := t.Field(i).Name
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+
+		oldField := oldV.Field(i)
+		newField := newV.Field(i)
+
+		switch oldField.Kind() {
+		case reflect.Ptr:
+			if !oldField.IsNil() && !newField.IsNil() && oldField.Elem().Kind() == reflect.Struct {
+				diffStruct(oldField.Elem(), newField.Elem(), fieldPath, changes)
+				continue
+			}
+
+			oldVal := derefInterface(oldField)
+			newVal := derefInterface(newField)
+			if !reflect.DeepEqual(oldVal, newVal) {
+				*changes = append(*changes, ConfigChange{Path: fieldPath, Old: oldVal, New: newVal})
+			}
+		case reflect.Struct:
+			diffStruct(oldField, newField, fieldPath, changes)
+		default:
+			if !reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+				*changes = append(*changes, ConfigChange{Path: fieldPath, Old: oldField.Interface(), New: newField.Interface()})
+			}
+		}
+	}
+}
+
+// derefInterface returns the value v points to, or nil if v is a nil pointer.
+func derefInterface(v reflect.Value) interface{} {
+	if v.IsNil() {
+		return nil
+	}
+
+	return v.Elem().Interface()
+}
+
+// changeSubscribers implements a simple pub/sub for configuration changes. Each store embeds
+// one and calls notifySubscribers whenever a Load or Set successfully replaces its config, so
+// that callers have a single hook to react to configuration changes instead of each racing to
+// re-read Get().
+type changeSubscribers struct {
+	mut         sync.Mutex
+	nextID      int
+	subscribers map[int]func(old, new *model.Config)
+}
+
+// Subscribe registers fn to be called with the old and new configuration every time this store
+// successfully loads or sets a new configuration. It returns a function that unsubscribes fn.
+func (cs *changeSubscribers) Subscribe(fn func(old, new *model.Config)) func() {
+	cs.mut.Lock()
+	defer cs.mut.Unlock()
+
+	if cs.subscribers == nil {
+		cs.subscribers = make(map[int]func(old, new *model.Config))
+	}
+
+	id := cs.nextID
+	cs.nextID++
+	cs.subscribers[id] = fn
+
+	return func() {
+		cs.mut.Lock()
+		defer cs.mut.Unlock()
+
+		delete(cs.subscribers, id)
+	}
+}
+
+// notifySubscribers invokes every subscriber with old and new, skipping the call entirely if
+// there's nothing to tell them (old and new are identical or either is nil because this is the
+// very first load).
+func (cs *changeSubscribers) notifySubscribers(old, new *model.Config) {
+	if old == nil || new == nil || len(Diff(old, new)) == 0 {
+		return
+	}
+
+	cs.mut.Lock()
+	fns := make([]func(old, new *model.Config), 0, len(cs.subscribers))
+	for _, fn := range cs.subscribers {
+		fns = append(fns, fn)
+	}
+	cs.mut.Unlock()
+
+	for _, fn := range fns {
+		fn(old, new)
+	}
+}
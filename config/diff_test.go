@@ -0,0 +1,132 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestDiffNilConfigs(t *testing.T) {
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+
+	if changes := Diff(nil, cfg); len(changes) != 0 {
+		t.Errorf("expected no changes when old is nil, got %v", changes)
+	}
+	if changes := Diff(cfg, nil); len(changes) != 0 {
+		t.Errorf("expected no changes when new is nil, got %v", changes)
+	}
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	old := &model.Config{}
+	old.SetDefaults()
+
+	new, err := cloneConfig(old)
+	if err != nil {
+		t.Fatalf("cloneConfig failed: %v", err)
+	}
+
+	if changes := Diff(old, new); len(changes) != 0 {
+		t.Errorf("expected no changes between identical configs, got %v", changes)
+	}
+}
+
+func TestDiffDetectsScalarChange(t *testing.T) {
+	old := &model.Config{}
+	old.SetDefaults()
+
+	new, err := cloneConfig(old)
+	if err != nil {
+		t.Fatalf("cloneConfig failed: %v", err)
+	}
+	new.ServiceSettings.SiteURL = model.NewString("http://example.com")
+
+	changes := Diff(old, new)
+	if len(changes) != 1 {
+		t.Fatalf("expected exactly one change, got %v", changes)
+	}
+
+	change := changes[0]
+	if change.Path != "ServiceSettings.SiteURL" {
+		t.Errorf("Path = %q, want %q", change.Path, "ServiceSettings.SiteURL")
+	}
+	if change.Old != "" {
+		t.Errorf("Old = %v, want %q", change.Old, "")
+	}
+	if change.New != "http://example.com" {
+		t.Errorf("New = %v, want %q", change.New, "http://example.com")
+	}
+}
+
+func TestDiffDetectsNilToValueChange(t *testing.T) {
+	old := &model.Config{}
+	new := &model.Config{}
+	new.ServiceSettings.SiteURL = model.NewString("http://example.com")
+
+	changes := Diff(old, new)
+	if len(changes) != 1 {
+		t.Fatalf("expected exactly one change, got %v", changes)
+	}
+	if changes[0].Old != nil {
+		t.Errorf("Old = %v, want nil", changes[0].Old)
+	}
+	if changes[0].New != "http://example.com" {
+		t.Errorf("New = %v, want %q", changes[0].New, "http://example.com")
+	}
+}
+
+func TestChangeSubscribersNotifiesOnChange(t *testing.T) {
+	var cs changeSubscribers
+
+	var gotOld, gotNew *model.Config
+	calls := 0
+	unsubscribe := cs.Subscribe(func(old, new *model.Config) {
+		calls++
+		gotOld, gotNew = old, new
+	})
+
+	old := &model.Config{}
+	old.SetDefaults()
+	new, err := cloneConfig(old)
+	if err != nil {
+		t.Fatalf("cloneConfig failed: %v", err)
+	}
+	new.ServiceSettings.SiteURL = model.NewString("http://example.com")
+
+	cs.notifySubscribers(old, new)
+
+	if calls != 1 {
+		t.Fatalf("expected exactly one notification, got %d", calls)
+	}
+	if gotOld != old || gotNew != new {
+		t.Error("expected the subscriber to receive the exact old/new pointers passed in")
+	}
+
+	unsubscribe()
+	cs.notifySubscribers(new, old)
+	if calls != 1 {
+		t.Errorf("expected no further notifications after unsubscribing, got %d calls", calls)
+	}
+}
+
+func TestChangeSubscribersSkipsWhenNothingChanged(t *testing.T) {
+	var cs changeSubscribers
+
+	calls := 0
+	cs.Subscribe(func(old, new *model.Config) { calls++ })
+
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+
+	cs.notifySubscribers(cfg, cfg)
+	cs.notifySubscribers(nil, cfg)
+	cs.notifySubscribers(cfg, nil)
+
+	if calls != 0 {
+		t.Errorf("expected no notifications, got %d", calls)
+	}
+}
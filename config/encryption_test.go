@@ -0,0 +1,146 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	return bytes.Repeat([]byte{0x42}, encryptionKeySize)
+}
+
+func TestIsEncryptedConfig(t *testing.T) {
+	if isEncryptedConfig([]byte(`{"ServiceSettings": {}}`)) {
+		t.Error("expected plaintext JSON to not be reported as encrypted")
+	}
+	if !isEncryptedConfig([]byte(encryptionMagicHeader + "garbage")) {
+		t.Error("expected data with the magic header to be reported as encrypted")
+	}
+}
+
+func TestEncryptDecryptConfigRoundTrip(t *testing.T) {
+	key := testKey(t)
+	plaintext := []byte(`{"ServiceSettings":{"SiteURL":"http://example.com"}}`)
+
+	encrypted, err := encryptConfig(key, plaintext)
+	if err != nil {
+		t.Fatalf("encryptConfig failed: %v", err)
+	}
+	if !isEncryptedConfig(encrypted) {
+		t.Fatal("expected encrypted output to carry the magic header")
+	}
+	if bytes.Contains(encrypted, plaintext) {
+		t.Fatal("expected encrypted output to not contain the plaintext")
+	}
+
+	decrypted, err := decryptConfig(key, encrypted)
+	if err != nil {
+		t.Fatalf("decryptConfig failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decrypted = %s, want %s", decrypted, plaintext)
+	}
+}
+
+func TestDecryptConfigWrongKey(t *testing.T) {
+	encrypted, err := encryptConfig(testKey(t), []byte(`{}`))
+	if err != nil {
+		t.Fatalf("encryptConfig failed: %v", err)
+	}
+
+	wrongKey := bytes.Repeat([]byte{0x24}, encryptionKeySize)
+	if _, err := decryptConfig(wrongKey, encrypted); err == nil {
+		t.Fatal("expected decryption with the wrong key to fail")
+	}
+}
+
+func TestDecryptConfigTruncated(t *testing.T) {
+	encrypted, err := encryptConfig(testKey(t), []byte(`{}`))
+	if err != nil {
+		t.Fatalf("encryptConfig failed: %v", err)
+	}
+
+	truncated := encrypted[:len(encryptionMagicHeader)+4]
+	if _, err := decryptConfig(testKey(t), truncated); err == nil {
+		t.Fatal("expected decryption of a truncated payload to fail")
+	}
+}
+
+func TestDecryptConfigNotEncrypted(t *testing.T) {
+	if _, err := decryptConfig(testKey(t), []byte(`{}`)); err == nil {
+		t.Fatal("expected decrypting plaintext to fail")
+	}
+}
+
+func TestDecodeEncryptionKey(t *testing.T) {
+	if _, err := decodeEncryptionKey("not-hex"); err == nil {
+		t.Error("expected a non-hex key to fail")
+	}
+	if _, err := decodeEncryptionKey("2a"); err == nil {
+		t.Error("expected a key of the wrong length to fail")
+	}
+
+	key, err := decodeEncryptionKey(strings.Repeat("42", encryptionKeySize))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(key) != encryptionKeySize {
+		t.Errorf("len(key) = %d, want %d", len(key), encryptionKeySize)
+	}
+}
+
+func TestEncryptDecryptFileRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-encryption-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	plaintext := []byte(`{"ServiceSettings":{"SiteURL":"http://example.com"}}`)
+	if err := ioutil.WriteFile(path, plaintext, 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	key := testKey(t)
+
+	if err := EncryptFile(path, key); err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+	encrypted, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read encrypted config: %v", err)
+	}
+	if !isEncryptedConfig(encrypted) {
+		t.Fatal("expected file to be encrypted after EncryptFile")
+	}
+
+	// Encrypting an already-encrypted file is a no-op.
+	if err := EncryptFile(path, key); err != nil {
+		t.Fatalf("EncryptFile on an already-encrypted file failed: %v", err)
+	}
+
+	if err := DecryptFile(path, key); err != nil {
+		t.Fatalf("DecryptFile failed: %v", err)
+	}
+	decrypted, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read decrypted config: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decrypted = %s, want %s", decrypted, plaintext)
+	}
+
+	// Decrypting an already-plaintext file is a no-op.
+	if err := DecryptFile(path, key); err != nil {
+		t.Fatalf("DecryptFile on an already-plaintext file failed: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// environmentVariablePrefix is the prefix an environment variable must carry to be considered
+// a configuration override, e.g. MM_SERVICESETTINGS_SITEURL overrides ServiceSettings.SiteURL.
+const environmentVariablePrefix = "MM_"
+
+// generateEnvironmentMap parses the given environment (as returned by os.Environ()) into a map
+// of MM_-prefixed variable name to raw string value.
+func generateEnvironmentMap(env []string) map[string]string {
+	overrides := make(map[string]string)
+
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, environmentVariablePrefix) {
+			continue
+		}
+
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		overrides[parts[0]] = parts[1]
+	}
+
+	return overrides
+}
+
+// applyEnvironmentMap overlays cfg with any value found in env that maps to one of its fields,
+// returning the dotted field paths (e.g. "ServiceSettings.SiteURL") that were overridden.
+func applyEnvironmentMap(cfg *model.Config, env map[string]string) map[string]interface{} {
+	overrides := make(map[string]interface{})
+
+	if len(env) > 0 {
+		applyEnvironmentMapToStruct(reflect.ValueOf(cfg).Elem(), "", env, overrides)
+	}
+
+	return overrides
+}
+
+// applyEnvironmentMapToStruct walks v, recursing into nested settings structs, and overrides
+// any settable field whose corresponding MM_ environment variable is present in env.
+func applyEnvironmentMapToStruct(v reflect.Value, path string, env map[string]string, overrides map[string]interface{}) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		fieldPath := t.Field(i).Name
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+
+		switch field.Kind() {
+		case reflect.Ptr:
+			// field.Elem().Kind() is Invalid (not Struct) for a nil pointer, so the element
+			// kind has to come from the static type instead: that's what lets a field that's
+			// nil because it's simply absent from config.json still be recognized and, for
+			// scalar fields, allocated so its env var can be applied.
+			if field.Type().Elem().Kind() == reflect.Struct {
+				if !field.IsNil() {
+					applyEnvironmentMapToStruct(field.Elem(), fieldPath, env, overrides)
+				}
+				continue
+			}
+
+			raw, ok := env[environmentKeyFor(fieldPath)]
+			if !ok {
+				continue
+			}
+
+			// Parse into a fresh value so that a raw value that can't be applied leaves the
+			// field exactly as it was, rather than allocated to its zero value.
+			value := reflect.New(field.Type().Elem())
+			if !setValueFromString(value.Elem(), raw) {
+				continue
+			}
+
+			field.Set(value)
+			overrides[fieldPath] = raw
+		case reflect.Struct:
+			applyEnvironmentMapToStruct(field, fieldPath, env, overrides)
+		}
+	}
+}
+
+// environmentKeyFor returns the environment variable name that overrides the given dotted
+// field path, e.g. "ServiceSettings.SiteURL" becomes "MM_SERVICESETTINGS_SITEURL".
+func environmentKeyFor(fieldPath string) string {
+	return environmentVariablePrefix + strings.ToUpper(strings.Replace(fieldPath, ".", "_", -1))
+}
+
+// setValueFromString assigns raw, converted to elem's underlying type, to elem. It returns
+// false if elem's kind isn't one supported by environment overrides or raw can't be parsed.
+func setValueFromString(elem reflect.Value, raw string) bool {
+	switch elem.Kind() {
+	case reflect.String:
+		elem.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return false
+		}
+		elem.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return false
+		}
+		elem.SetInt(n)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// fieldByPath resolves a dotted field path such as "ServiceSettings.SiteURL" against v,
+// returning the zero Value if any segment along the way doesn't exist or passes through a nil
+// pointer. The returned field isn't checked for settability; callers check CanSet themselves.
+func fieldByPath(v reflect.Value, path string) reflect.Value {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+
+		v = v.FieldByName(name)
+		if !v.IsValid() {
+			return reflect.Value{}
+		}
+	}
+
+	return v
+}
+
+// cloneConfig returns a deep copy of cfg by round-tripping it through JSON, matching the
+// approach the rest of the config package already uses for equality comparisons.
+func cloneConfig(cfg *model.Config) (*model.Config, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var clone model.Config
+	if err := json.Unmarshal(b, &clone); err != nil {
+		return nil, err
+	}
+
+	return &clone, nil
+}
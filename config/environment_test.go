@@ -0,0 +1,95 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerateEnvironmentMap(t *testing.T) {
+	env := []string{
+		"MM_SERVICESETTINGS_SITEURL=http://example.com",
+		"MM_CLUSTERSETTINGS_ENABLE=true",
+		"PATH=/usr/bin",
+		"NOTANENVVAR",
+	}
+
+	got := generateEnvironmentMap(env)
+
+	want := map[string]string{
+		"MM_SERVICESETTINGS_SITEURL": "http://example.com",
+		"MM_CLUSTERSETTINGS_ENABLE":  "true",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("generateEnvironmentMap() = %#v, want %#v", got, want)
+	}
+}
+
+func TestEnvironmentKeyFor(t *testing.T) {
+	if got, want := environmentKeyFor("ServiceSettings.SiteURL"), "MM_SERVICESETTINGS_SITEURL"; got != want {
+		t.Errorf("environmentKeyFor() = %q, want %q", got, want)
+	}
+}
+
+// testSettings/testConfig stand in for the relevant shape of model.Config without depending
+// on it, so these tests exercise the reflection walk without needing the full model package.
+type testSettings struct {
+	SiteURL *string
+	Port    *int
+}
+
+type testConfig struct {
+	ServiceSettings testSettings
+}
+
+func TestApplyEnvironmentMapToStruct_AllocatesNilPointer(t *testing.T) {
+	cfg := &testConfig{}
+	env := map[string]string{"MM_SERVICESETTINGS_SITEURL": "http://example.com"}
+	overrides := map[string]interface{}{}
+
+	applyEnvironmentMapToStruct(reflect.ValueOf(cfg).Elem(), "", env, overrides)
+
+	if cfg.ServiceSettings.SiteURL == nil {
+		t.Fatal("expected SiteURL to be allocated and set, got nil")
+	}
+	if *cfg.ServiceSettings.SiteURL != "http://example.com" {
+		t.Errorf("SiteURL = %q, want %q", *cfg.ServiceSettings.SiteURL, "http://example.com")
+	}
+	if overrides["ServiceSettings.SiteURL"] != "http://example.com" {
+		t.Errorf("overrides[ServiceSettings.SiteURL] = %v, want %q", overrides["ServiceSettings.SiteURL"], "http://example.com")
+	}
+}
+
+func TestApplyEnvironmentMapToStruct_LeavesUnsetFieldsAlone(t *testing.T) {
+	cfg := &testConfig{}
+	overrides := map[string]interface{}{}
+
+	applyEnvironmentMapToStruct(reflect.ValueOf(cfg).Elem(), "", map[string]string{}, overrides)
+
+	if cfg.ServiceSettings.SiteURL != nil {
+		t.Errorf("expected SiteURL to remain nil, got %v", *cfg.ServiceSettings.SiteURL)
+	}
+	if len(overrides) != 0 {
+		t.Errorf("expected no overrides, got %v", overrides)
+	}
+}
+
+func TestFieldByPath(t *testing.T) {
+	site := "http://example.com"
+	cfg := &testConfig{ServiceSettings: testSettings{SiteURL: &site}}
+
+	v := fieldByPath(reflect.ValueOf(cfg).Elem(), "ServiceSettings.SiteURL")
+	if !v.IsValid() {
+		t.Fatal("expected a valid field, got the zero Value")
+	}
+	if got := v.Interface().(*string); *got != site {
+		t.Errorf("fieldByPath() = %q, want %q", *got, site)
+	}
+
+	if v := fieldByPath(reflect.ValueOf(cfg).Elem(), "ServiceSettings.Missing"); v.IsValid() {
+		t.Errorf("expected an invalid field for a missing path, got %v", v)
+	}
+}
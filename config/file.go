@@ -5,11 +5,13 @@ package config
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 
 	"github.com/pkg/errors"
 
@@ -18,6 +20,9 @@ import (
 	"github.com/mattermost/mattermost-server/utils/fileutils"
 )
 
+// environ is a var so tests can stub out the process environment.
+var environ = os.Environ
+
 var (
 	ErrReadOnlyConfiguration = errors.New("configuration is read-only")
 )
@@ -25,10 +30,24 @@ var (
 // FileStore is a config store backed by a file such as config/config.json.
 type FileStore struct {
 	commonStore
+	changeSubscribers
 
 	path    string
 	watch   bool
 	watcher *watcher
+
+	// fileConfig holds the configuration as last read from path, before any environment
+	// variable overlay is applied. persist reverts every environment-overridden field of the
+	// config it writes to its value here, so that environment-sourced overrides are never
+	// baked into the file.
+	fileConfig *model.Config
+
+	// environmentOverrides tracks the dotted field paths (e.g. "ServiceSettings.SiteURL")
+	// currently overridden by an environment variable, along with the raw string value.
+	environmentOverrides map[string]interface{}
+
+	// maxBackups is the number of rolling config.json backups to keep; see SetMaxBackups.
+	maxBackups int
 }
 
 // NewFileStore creates a new instance of a config store backed by the given file path.
@@ -88,29 +107,56 @@ func resolveConfigFilePath(path string) (string, error) {
 
 // Set replaces the current configuration in its entirety, without updating the backing store.
 func (fs *FileStore) Set(newCfg *model.Config) (*model.Config, error) {
-	return fs.commonStore.set(newCfg, func(cfg *model.Config) error {
+	old, err := fs.commonStore.set(newCfg, func(cfg *model.Config) error {
 		if *fs.config.ClusterSettings.Enable && *fs.config.ClusterSettings.ReadOnlyConfig {
 			return ErrReadOnlyConfiguration
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	fs.notifySubscribers(old, fs.commonStore.Get())
+
+	return old, nil
 }
 
 // persist writes the configuration to the configured file.
+//
+// Only file-sourced values are written: any field currently overridden by an environment
+// variable is reverted to its last file-sourced value first, so Load-ing the file back never
+// picks up a value that only ever existed because of the environment.
 func (fs *FileStore) persist(cfg *model.Config) error {
 	fs.stopWatcher()
 
-	b, err := marshalConfig(cfg)
+	toWrite, err := fs.withoutEnvironmentOverrides(cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to strip environment overrides")
+	}
+
+	b, err := marshalConfig(toWrite)
 	if err != nil {
 		return errors.Wrap(err, "failed to serialize")
 	}
 
-	err = ioutil.WriteFile(fs.path, b, 0644)
+	key, err := loadEncryptionKey()
 	if err != nil {
-		return errors.Wrap(err, "failed to write file")
+		return errors.Wrap(err, "failed to load config encryption key")
+	}
+	if key != nil {
+		if b, err = encryptConfig(key, b); err != nil {
+			return errors.Wrap(err, "failed to encrypt config")
+		}
 	}
 
+	if err = fs.writeAtomic(b); err != nil {
+		return err
+	}
+
+	fs.fileConfig = toWrite
+
 	if fs.watch {
 		if err = fs.startWatcher(); err != nil {
 			mlog.Error("failed to start config watcher", mlog.String("path", fs.path), mlog.Err(err))
@@ -122,6 +168,8 @@ func (fs *FileStore) persist(cfg *model.Config) error {
 
 // Load updates the current configuration from the backing store.
 func (fs *FileStore) Load() (err error) {
+	old := fs.commonStore.Get()
+
 	var needsSave bool
 	var f io.ReadCloser
 
@@ -149,7 +197,110 @@ func (fs *FileStore) Load() (err error) {
 		}
 	}()
 
-	return fs.commonStore.load(f, needsSave, fs.persist)
+	fileBytes, err := ioutil.ReadAll(f)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", fs.path)
+	}
+
+	if isEncryptedConfig(fileBytes) {
+		key, keyErr := loadEncryptionKey()
+		if keyErr != nil {
+			return errors.Wrap(keyErr, "failed to load config encryption key")
+		}
+		if key == nil {
+			return errors.New("config is encrypted but no MM_CONFIG_KEY or MM_CONFIG_KEYFILE is set")
+		}
+
+		if fileBytes, err = decryptConfig(key, fileBytes); err != nil {
+			return errors.Wrap(err, "failed to decrypt config")
+		}
+	}
+
+	var fileCfg model.Config
+	if err = json.Unmarshal(fileBytes, &fileCfg); err != nil {
+		return errors.Wrap(err, "failed to unmarshal file config")
+	}
+
+	mergedCfg, err := cloneConfig(&fileCfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to clone file config")
+	}
+
+	// Apply the environment variable overlay after the file is unmarshaled but before
+	// commonStore.load runs validation and fills in defaults, so that an env-sourced value
+	// is treated exactly like a file-sourced one for the rest of the load.
+	overrides := applyEnvironmentMap(mergedCfg, generateEnvironmentMap(environ()))
+
+	mergedBytes, err := marshalConfig(mergedCfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal merged config")
+	}
+
+	// fileConfig and environmentOverrides must be in place before commonStore.load runs: when
+	// needsSave is true, load calls fs.persist synchronously, and persist strips overridden
+	// fields using exactly these two fields.
+	fs.configLock.Lock()
+	fs.fileConfig = &fileCfg
+	fs.environmentOverrides = overrides
+	fs.configLock.Unlock()
+
+	if err = fs.commonStore.load(bytes.NewReader(mergedBytes), needsSave, fs.persist); err != nil {
+		return err
+	}
+
+	fs.notifySubscribers(old, fs.commonStore.Get())
+
+	return nil
+}
+
+// GetEnvironmentOverrides returns the configuration field paths (e.g. "ServiceSettings.SiteURL")
+// that are currently overridden by an environment variable, keyed to the raw value of that
+// variable. Admins and APIs can use this to indicate which settings are locked by the
+// environment and therefore can't be changed via the System Console.
+func (fs *FileStore) GetEnvironmentOverrides() map[string]interface{} {
+	fs.configLock.RLock()
+	defer fs.configLock.RUnlock()
+
+	overrides := make(map[string]interface{}, len(fs.environmentOverrides))
+	for k, v := range fs.environmentOverrides {
+		overrides[k] = v
+	}
+
+	return overrides
+}
+
+// withoutEnvironmentOverrides returns a copy of cfg with every field tracked in
+// fs.environmentOverrides reset to its last file-sourced value, so persist never writes an
+// environment-sourced value back to disk.
+func (fs *FileStore) withoutEnvironmentOverrides(cfg *model.Config) (*model.Config, error) {
+	clone, err := cloneConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.fileConfig == nil || len(fs.environmentOverrides) == 0 {
+		return clone, nil
+	}
+
+	src := reflect.ValueOf(fs.fileConfig).Elem()
+	dst := reflect.ValueOf(clone).Elem()
+
+	for path := range fs.environmentOverrides {
+		srcField := fieldByPath(src, path)
+		dstField := fieldByPath(dst, path)
+		if !srcField.IsValid() || !dstField.IsValid() || !dstField.CanSet() {
+			continue
+		}
+
+		if srcField.Kind() == reflect.Ptr && !srcField.IsNil() && dstField.Kind() == reflect.Ptr && !dstField.IsNil() {
+			dstField.Elem().Set(srcField.Elem())
+			continue
+		}
+
+		dstField.Set(srcField)
+	}
+
+	return clone, nil
 }
 
 // Save writes the current configuration to the backing store.
@@ -205,4 +356,4 @@ func (fs *FileStore) Close() error {
 	fs.stopWatcher()
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package config
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// MemoryStore is a config store that keeps its configuration purely in memory. It implements
+// the same Store interface as FileStore and DatabaseStore but never touches disk, which makes
+// it the store of choice for tests that would otherwise need DisableConfigWatch or a throwaway
+// config.json just to avoid filesystem churn.
+type MemoryStore struct {
+	commonStore
+	changeSubscribers
+
+	skipValidation bool
+	persistCalls   int
+}
+
+// MemoryStoreOptions configures the initial state of a MemoryStore.
+type MemoryStoreOptions struct {
+	// Config, if set, seeds the store instead of the default configuration.
+	Config *model.Config
+
+	// SkipValidation disables IsValid checks on Set (including the seeding Set done on
+	// construction), matching the needs of tests
+	// that intentionally exercise partial or invalid configurations.
+	SkipValidation bool
+}
+
+// NewMemoryStore creates a new instance of a config store that keeps its configuration only
+// in memory, seeded with the default configuration.
+func NewMemoryStore() (*MemoryStore, error) {
+	return NewMemoryStoreWithOptions(&MemoryStoreOptions{})
+}
+
+// NewMemoryStoreWithOptions creates a new instance of a config store that keeps its
+// configuration only in memory, seeded from options.
+func NewMemoryStoreWithOptions(options *MemoryStoreOptions) (ms *MemoryStore, err error) {
+	ms = &MemoryStore{
+		skipValidation: options.SkipValidation,
+	}
+
+	cfg := options.Config
+	if cfg == nil {
+		cfg = &model.Config{}
+	}
+	cfg.SetDefaults()
+
+	// Seed through Set, not commonStore.load, so that options.SkipValidation is honored on
+	// construction: commonStore.load always validates, which would make SkipValidation unusable
+	// for tests that need to seed a deliberately invalid configuration.
+	if _, err = ms.Set(cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to set seed config")
+	}
+
+	return ms, nil
+}
+
+// SetSkipValidation configures whether Set validates the configuration. Tests that
+// need to exercise an intentionally invalid configuration can disable validation entirely.
+func (ms *MemoryStore) SetSkipValidation(skip bool) {
+	ms.configLock.Lock()
+	defer ms.configLock.Unlock()
+
+	ms.skipValidation = skip
+}
+
+// Set replaces the current configuration in its entirety, without persisting it anywhere.
+func (ms *MemoryStore) Set(newCfg *model.Config) (*model.Config, error) {
+	old, err := ms.commonStore.set(newCfg, func(cfg *model.Config) error {
+		if ms.skipValidation {
+			return nil
+		}
+
+		return cfg.IsValid()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ms.notifySubscribers(old, ms.commonStore.Get())
+
+	return old, nil
+}
+
+// persist is a no-op: MemoryStore never writes its configuration anywhere. It still counts
+// invocations so tests can assert that Save was (or wasn't) called.
+func (ms *MemoryStore) persist(cfg *model.Config) error {
+	ms.persistCalls++
+
+	return nil
+}
+
+// Load is a no-op: there is nothing to reload from, since MemoryStore has no backing store.
+func (ms *MemoryStore) Load() error {
+	return nil
+}
+
+// Save is a no-op: MemoryStore never writes its configuration anywhere.
+func (ms *MemoryStore) Save() error {
+	ms.configLock.Lock()
+	defer ms.configLock.Unlock()
+
+	return ms.persist(ms.config)
+}
+
+// String returns a fixed identifier, since MemoryStore isn't backed by any addressable store.
+func (ms *MemoryStore) String() string {
+	return "memory://"
+}
+
+// Close is a no-op: MemoryStore holds no resources that need to be released.
+func (ms *MemoryStore) Close() error {
+	return nil
+}
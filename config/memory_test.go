@@ -0,0 +1,57 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestNewMemoryStoreWithOptions_Defaults(t *testing.T) {
+	ms, err := NewMemoryStore()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ms.Get().ServiceSettings.SiteURL == nil {
+		t.Fatal("expected defaults to be applied, got nil SiteURL")
+	}
+}
+
+func invalidConfig() *model.Config {
+	cfg := &model.Config{}
+	cfg.ServiceSettings.SiteURL = model.NewString("not-a-url")
+	return cfg
+}
+
+func TestNewMemoryStoreWithOptions_SkipValidationAllowsInvalidSeed(t *testing.T) {
+	if _, err := NewMemoryStoreWithOptions(&MemoryStoreOptions{Config: invalidConfig(), SkipValidation: false}); err == nil {
+		t.Fatal("expected seeding an invalid config to fail without SkipValidation")
+	}
+
+	ms, err := NewMemoryStoreWithOptions(&MemoryStoreOptions{Config: invalidConfig(), SkipValidation: true})
+	if err != nil {
+		t.Fatalf("expected SkipValidation to allow an invalid seed, got error: %v", err)
+	}
+	if ms == nil {
+		t.Fatal("expected a non-nil store")
+	}
+}
+
+func TestMemoryStoreSetRespectsSkipValidation(t *testing.T) {
+	ms, err := NewMemoryStore()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := ms.Set(invalidConfig()); err == nil {
+		t.Fatal("expected Set to validate by default")
+	}
+
+	ms.SetSkipValidation(true)
+	if _, err := ms.Set(invalidConfig()); err != nil {
+		t.Fatalf("expected Set to skip validation, got error: %v", err)
+	}
+}